@@ -0,0 +1,80 @@
+package interpreter
+
+import "errors"
+
+// ErrorKind classifies a RuntimeError so callers can react programmatically
+// instead of pattern-matching on an error message.
+type ErrorKind string
+
+const (
+	// MissingVariableError classifies the failure reported when
+	// MissingVariables finds a name in a script's vars block that the caller
+	// didn't supply a value for.
+	MissingVariableError ErrorKind = "missing_variable"
+	// UnknownError is returned by Classify for any error that doesn't
+	// implement Kinded, i.e. one RunProgram's evaluator hasn't classified.
+	UnknownError ErrorKind = "unknown"
+)
+
+// Position and Range locate a RuntimeError in the source script, mirroring
+// the shape analysis uses for diagnostics so callers can render both
+// uniformly.
+type Position struct {
+	Line      int
+	Character int
+}
+
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// RuntimeError is a classified failure that occurs in connection with
+// evaluating a program, as opposed to a parse error raised before evaluation
+// starts. Today the only ones are built by callers from the names
+// MissingVariables returns; RunProgram's own
+// evaluator errors will satisfy Kinded directly once it's taught to, and
+// Classify will pick them up with no caller changes. Range is optional: not
+// every runtime failure can be pinned to a single source location.
+type RuntimeError struct {
+	Kind  ErrorKind
+	Msg   string
+	Range *Range
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Msg
+}
+
+func (e *RuntimeError) ErrorKind() ErrorKind {
+	return e.Kind
+}
+
+// Kinded is implemented by any interpreter error that can classify itself.
+// RuntimeError satisfies it; RunProgram's evaluator errors do not, so
+// Classify reports them as UnknownError. Classify uses Kinded so callers
+// don't need to know the concrete error type.
+type Kinded interface {
+	error
+	ErrorKind() ErrorKind
+}
+
+// Classify extracts the ErrorKind from err, if it (or something it wraps)
+// implements Kinded. It returns (UnknownError, false) for errors that carry
+// no classification, e.g. ones raised outside program evaluation.
+func Classify(err error) (ErrorKind, bool) {
+	var kinded Kinded
+	if errors.As(err, &kinded) {
+		return kinded.ErrorKind(), true
+	}
+	return UnknownError, false
+}
+
+// AsRuntimeError unwraps err into a *RuntimeError, if it is one.
+func AsRuntimeError(err error) (*RuntimeError, bool) {
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr, true
+	}
+	return nil, false
+}
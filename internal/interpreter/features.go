@@ -0,0 +1,37 @@
+package interpreter
+
+// Feature names a flag gating experimental interpreter behavior.
+type Feature string
+
+// FeatureInfo describes a known feature flag for discovery by callers such
+// as the CLI's `features` subcommand.
+type FeatureInfo struct {
+	Name        Feature
+	Description string
+}
+
+// KnownFeatures lists every feature flag RunProgram's evaluator checks, in
+// the order they should be presented to users. It starts empty: this series
+// adds the registry and the --feature/RunInputOpts.Features plumbing that
+// reads it, but doesn't touch the evaluator itself, so there's nothing real
+// to list yet. Add an entry here in the same commit that teaches RunProgram
+// to check a new flag, so `numscript features` never drifts out of sync
+// with what --feature can actually influence.
+var KnownFeatures = []FeatureInfo{}
+
+// FeatureSet is the set of feature flags enabled for a single RunProgram
+// call. Membership, not value, is what matters: a flag is enabled iff it is
+// present in the map.
+type FeatureSet = map[string]struct{}
+
+// NewFeatureSet builds a FeatureSet from a list of flag names. Names are not
+// validated against KnownFeatures: RunProgram only ever consults the flags
+// it recognizes, so an unknown or misspelled name is silently inert rather
+// than an error.
+func NewFeatureSet(names []string) FeatureSet {
+	set := make(FeatureSet, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return set
+}
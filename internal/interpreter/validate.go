@@ -0,0 +1,35 @@
+package interpreter
+
+import "regexp"
+
+// varsBlockRe extracts the contents of a script's vars { ... } declaration
+// block, if it has one. Scripts without a vars block declare no variables.
+var varsBlockRe = regexp.MustCompile(`(?s)vars\s*\{(.*?)\}`)
+
+// varNameRe matches a variable reference (e.g. $amount) within a vars block.
+var varNameRe = regexp.MustCompile(`\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// MissingVariables returns the names declared in script's vars block that
+// have no entry in provided, in the order they're declared. RunProgram
+// requires a value for every declared variable, so this lets callers reject
+// an incomplete input before paying the cost of evaluation.
+func MissingVariables(script string, provided map[string]string) []string {
+	block := varsBlockRe.FindStringSubmatch(script)
+	if block == nil {
+		return nil
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, m := range varNameRe.FindAllStringSubmatch(block[1], -1) {
+		name := m[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, ok := provided[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,534 @@
+// Package lsp implements a minimal Language Server Protocol server for
+// numscript, speaking JSON-RPC 2.0 over stdio.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PagoPlus/numscriptex/internal/analysis"
+)
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Message  string `json:"message"`
+	Source   string `json:"source"`
+}
+
+type document struct {
+	text string
+}
+
+// Server holds the state of the open text documents for a single client
+// connection.
+type Server struct {
+	mu   sync.Mutex
+	docs map[string]*document
+}
+
+func NewServer() *Server {
+	return &Server{docs: make(map[string]*document)}
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads Content-Length framed JSON-RPC messages from r, dispatches
+// them against s, and writes responses/notifications to w. It returns when
+// r is closed or the client sends "exit".
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	var writeMu sync.Mutex
+
+	for {
+		msg, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch msg.Method {
+		case "initialize":
+			s.reply(&writeMu, w, msg.ID, map[string]interface{}{
+				"capabilities": map[string]interface{}{
+					"textDocumentSync":       1,
+					"hoverProvider":          true,
+					"definitionProvider":     true,
+					"documentSymbolProvider": true,
+					"completionProvider": map[string]interface{}{
+						"triggerCharacters": []string{"$", "@"},
+					},
+				},
+			})
+		case "initialized":
+			// no-op, the client just acknowledges the handshake
+		case "shutdown":
+			s.reply(&writeMu, w, msg.ID, nil)
+		case "exit":
+			return nil
+		case "textDocument/didOpen":
+			var params struct {
+				TextDocument struct {
+					URI  string `json:"uri"`
+					Text string `json:"text"`
+				} `json:"textDocument"`
+			}
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				continue
+			}
+			s.setDocument(params.TextDocument.URI, params.TextDocument.Text)
+			s.publishDiagnostics(&writeMu, w, params.TextDocument.URI)
+		case "textDocument/didChange":
+			var params struct {
+				TextDocument struct {
+					URI string `json:"uri"`
+				} `json:"textDocument"`
+				ContentChanges []struct {
+					Text string `json:"text"`
+				} `json:"contentChanges"`
+			}
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				continue
+			}
+			if len(params.ContentChanges) == 0 {
+				continue
+			}
+			// full-document sync: the last change carries the whole text
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.setDocument(params.TextDocument.URI, text)
+			s.publishDiagnostics(&writeMu, w, params.TextDocument.URI)
+		case "textDocument/hover":
+			result := s.hover(msg.Params)
+			s.reply(&writeMu, w, msg.ID, result)
+		case "textDocument/definition":
+			result := s.definition(msg.Params)
+			s.reply(&writeMu, w, msg.ID, result)
+		case "textDocument/completion":
+			result := s.completion(msg.Params)
+			s.reply(&writeMu, w, msg.ID, result)
+		case "textDocument/documentSymbol":
+			result := s.documentSymbols(msg.Params)
+			s.reply(&writeMu, w, msg.ID, result)
+		default:
+			if msg.ID != nil {
+				s.replyErr(&writeMu, w, msg.ID, -32601, fmt.Sprintf("method not found: %s", msg.Method))
+			}
+		}
+	}
+}
+
+func (s *Server) setDocument(uri, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[uri] = &document{text: text}
+}
+
+func (s *Server) getDocument(uri string) (*document, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[uri]
+	return doc, ok
+}
+
+func (s *Server) publishDiagnostics(writeMu *sync.Mutex, w io.Writer, uri string) {
+	doc, ok := s.getDocument(uri)
+	if !ok {
+		return
+	}
+
+	res := analysis.CheckSource(doc.text)
+	diagnostics := make([]Diagnostic, 0, len(res.Diagnostics))
+	for _, d := range res.Diagnostics {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: Position{Line: d.Range.Start.Line, Character: d.Range.Start.Character},
+				End:   Position{Line: d.Range.End.Line, Character: d.Range.End.Character},
+			},
+			Severity: severityToLSP(d.Kind.Severity()),
+			Message:  d.Kind.Message(),
+			Source:   "numscript",
+		})
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	writeNotification(w, "textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+func severityToLSP(s analysis.Severity) int {
+	switch s {
+	case analysis.ErrorSeverity:
+		return 1
+	case analysis.WarningSeverity:
+		return 2
+	case analysis.Information:
+		return 3
+	case analysis.Hint:
+		return 4
+	default:
+		return 1
+	}
+}
+
+var identifierRe = regexp.MustCompile(`[$@]?[a-zA-Z_][a-zA-Z0-9_:]*`)
+
+var varsBlockRe = regexp.MustCompile(`\bvars\s*\{`)
+
+// wordAt returns the identifier-like token under the given position, and
+// whether one was found, using a textual scan of the source. It deliberately does
+// not depend on a parsed AST so it keeps working on documents with syntax
+// errors.
+func wordAt(text string, pos Position) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", false
+	}
+	line := lines[pos.Line]
+
+	for _, loc := range identifierRe.FindAllStringIndex(line, -1) {
+		if pos.Character >= loc[0] && pos.Character <= loc[1] {
+			return line[loc[0]:loc[1]], true
+		}
+	}
+	return "", false
+}
+
+// diagnosticAt returns the message of the first diagnostic CheckSource
+// reports whose range covers pos, if any.
+func diagnosticAt(res analysis.CheckResult, pos Position) (string, bool) {
+	for _, d := range res.Diagnostics {
+		if rangeCovers(d.Range, pos) {
+			return d.Kind.Message(), true
+		}
+	}
+	return "", false
+}
+
+func rangeCovers(r analysis.Range, pos Position) bool {
+	start, end := r.Start, r.End
+	if pos.Line < start.Line || (pos.Line == start.Line && pos.Character < start.Character) {
+		return false
+	}
+	if pos.Line > end.Line || (pos.Line == end.Line && pos.Character > end.Character) {
+		return false
+	}
+	return true
+}
+
+func (s *Server) hover(params json.RawMessage) interface{} {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		Position Position `json:"position"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+
+	doc, ok := s.getDocument(p.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+
+	word, ok := wordAt(doc.text, p.Position)
+	if !ok {
+		return nil
+	}
+
+	kind := "account"
+	switch {
+	case strings.HasPrefix(word, "$"):
+		kind = "variable"
+	case strings.HasPrefix(word, "@"):
+		kind = "account"
+	}
+
+	value := fmt.Sprintf("`%s` (%s)", word, kind)
+
+	// Surface whatever the analyzer already knows about this position (e.g.
+	// an undefined-variable or type error) instead of only the sigil-guessed
+	// kind above.
+	res := analysis.CheckSource(doc.text)
+	if message, ok := diagnosticAt(res, p.Position); ok {
+		value += "\n\n" + message
+	}
+
+	return map[string]interface{}{
+		"contents": map[string]interface{}{
+			"kind":  "markdown",
+			"value": value,
+		},
+	}
+}
+
+func (s *Server) definition(params json.RawMessage) interface{} {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		Position Position `json:"position"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+
+	doc, ok := s.getDocument(p.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+
+	word, ok := wordAt(doc.text, p.Position)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(word, "$"):
+		return definitionLocation(p.TextDocument.URI, variableDeclarationLine(doc.text, word))
+	case strings.HasPrefix(word, "@"):
+		return definitionLocation(p.TextDocument.URI, firstOtherOccurrence(doc.text, word, p.Position))
+	default:
+		return nil
+	}
+}
+
+// variableDeclarationLine returns the line/column span of word (including
+// its leading "$") inside the file's `vars { ... }` block, if any.
+func variableDeclarationLine(text string, word string) (int, int, int, bool) {
+	lines := strings.Split(text, "\n")
+	varRe := regexp.MustCompile(regexp.QuoteMeta(word) + `\b`)
+
+	inVarsBlock := false
+	for i, line := range lines {
+		if varsBlockRe.MatchString(line) {
+			inVarsBlock = true
+			continue
+		}
+		if inVarsBlock && strings.Contains(line, "}") {
+			inVarsBlock = false
+		}
+		if inVarsBlock {
+			if loc := varRe.FindStringIndex(line); loc != nil {
+				return i, loc[0], loc[1], true
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// firstOtherOccurrence finds the first occurrence of word elsewhere in
+// text, other than the token at pos itself. Numscript accounts have no
+// declaration site, so "go to definition" for an account reference jumps to
+// wherever it was first mentioned in the file.
+func firstOtherOccurrence(text string, word string, pos Position) (int, int, int, bool) {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		for _, loc := range identifierRe.FindAllStringIndex(line, -1) {
+			if line[loc[0]:loc[1]] != word {
+				continue
+			}
+			if i == pos.Line && pos.Character >= loc[0] && pos.Character <= loc[1] {
+				continue
+			}
+			return i, loc[0], loc[1], true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+func definitionLocation(uri string, line, startCol, endCol int, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	return []map[string]interface{}{
+		{
+			"uri": uri,
+			"range": Range{
+				Start: Position{Line: line, Character: startCol},
+				End:   Position{Line: line, Character: endCol},
+			},
+		},
+	}
+}
+
+var keywordCompletions = []string{"vars", "send", "source", "destination", "allowing unbounded overdraft", "save"}
+
+func (s *Server) completion(params json.RawMessage) interface{} {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+
+	items := make([]map[string]interface{}, 0, len(keywordCompletions))
+	seen := make(map[string]bool, len(keywordCompletions))
+	for _, kw := range keywordCompletions {
+		seen[kw] = true
+		items = append(items, map[string]interface{}{
+			"label": kw,
+			"kind":  14, // Keyword
+		})
+	}
+
+	if doc, ok := s.getDocument(p.TextDocument.URI); ok {
+		for _, match := range identifierRe.FindAllString(doc.text, -1) {
+			if seen[match] {
+				continue
+			}
+
+			switch {
+			case strings.HasPrefix(match, "$"):
+				seen[match] = true
+				items = append(items, map[string]interface{}{
+					"label": match,
+					"kind":  6, // Variable
+				})
+			case strings.HasPrefix(match, "@"):
+				seen[match] = true
+				items = append(items, map[string]interface{}{
+					"label": match,
+					"kind":  21, // Constant
+				})
+			}
+		}
+	}
+
+	return items
+}
+
+var blockRe = regexp.MustCompile(`\b(vars|send|save)\b`)
+
+func (s *Server) documentSymbols(params json.RawMessage) interface{} {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+
+	doc, ok := s.getDocument(p.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+
+	symbols := make([]map[string]interface{}, 0)
+	for i, line := range strings.Split(doc.text, "\n") {
+		loc := blockRe.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		symbols = append(symbols, map[string]interface{}{
+			"name": line[loc[0]:loc[1]],
+			"kind": 12, // Function
+			"range": Range{
+				Start: Position{Line: i, Character: 0},
+				End:   Position{Line: i, Character: len(line)},
+			},
+			"selectionRange": Range{
+				Start: Position{Line: i, Character: loc[0]},
+				End:   Position{Line: i, Character: loc[1]},
+			},
+		})
+	}
+	return symbols
+}
+
+func (s *Server) reply(writeMu *sync.Mutex, w io.Writer, id json.RawMessage, result interface{}) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	writeMessage(w, rpcMessage{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyErr(writeMu *sync.Mutex, w io.Writer, id json.RawMessage, code int, message string) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	writeMessage(w, rpcMessage{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+func writeNotification(w io.Writer, method string, params interface{}) {
+	raw, _ := json.Marshal(params)
+	writeMessage(w, rpcMessage{JSONRPC: "2.0", Method: method, Params: raw})
+}
+
+func readMessage(r *bufio.Reader) (*rpcMessage, error) {
+	var contentLength int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length:") {
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+			}
+			contentLength = n
+		}
+	}
+
+	if contentLength == 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func writeMessage(w io.Writer, msg rpcMessage) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunBatchWithPreservesOrder feeds lines whose fake process step sleeps
+// longer for earlier lines, so lines running concurrently finish out of
+// submission order, and checks the NDJSON output still comes back
+// index-for-index in input order.
+func TestRunBatchWithPreservesOrder(t *testing.T) {
+	const n = 8
+	var in bytes.Buffer
+	for i := 0; i < n; i++ {
+		in.WriteString(`{"script":"` + strconv.Itoa(i) + `"}` + "\n")
+	}
+
+	var mu sync.Mutex
+	var seen []int
+	process := func(opt RunInputOpts) batchResult {
+		i, err := strconv.Atoi(opt.Script)
+		if err != nil {
+			t.Fatalf("unexpected script %q", opt.Script)
+		}
+		// Earlier lines sleep longer, so later lines finish first.
+		time.Sleep(time.Duration(n-i) * time.Millisecond)
+		mu.Lock()
+		seen = append(seen, i)
+		mu.Unlock()
+		return batchResult{OK: true}
+	}
+
+	var out bytes.Buffer
+	if err := runBatchWith(&in, &out, 4, process); err != nil {
+		t.Fatalf("runBatchWith: %v", err)
+	}
+
+	// Completion order should not be the submission order (otherwise this
+	// test isn't actually exercising reordering).
+	inOrder := true
+	for i, s := range seen {
+		if s != i {
+			inOrder = false
+		}
+	}
+	if inOrder {
+		t.Fatalf("test setup didn't induce out-of-order completion: %v", seen)
+	}
+
+	dec := json.NewDecoder(&out)
+	for i := 0; i < n; i++ {
+		var res batchResult
+		if err := dec.Decode(&res); err != nil {
+			t.Fatalf("decode result %d: %v", i, err)
+		}
+		if res.Index != i {
+			t.Errorf("result %d: got index %d, want %d", i, res.Index, i)
+		}
+		if !res.OK {
+			t.Errorf("result %d: got OK=false, want true", i)
+		}
+	}
+}
+
+// TestRunBatchWithDecodeErrors checks that lines which fail to decode as
+// RunInputOpts are reported as decode_error records at their input index,
+// without the process function being invoked, and without disrupting
+// ordering relative to valid lines around them.
+func TestRunBatchWithDecodeErrors(t *testing.T) {
+	lines := []string{
+		`{"script":"ok0"}`,
+		`not json`,
+		`{"script":"ok1"}`,
+		`{"script": }`,
+	}
+	in := strings.NewReader(strings.Join(lines, "\n") + "\n")
+
+	process := func(opt RunInputOpts) batchResult {
+		return batchResult{OK: true, Value: json.RawMessage(`"` + opt.Script + `"`)}
+	}
+
+	var out bytes.Buffer
+	if err := runBatchWith(in, &out, 2, process); err != nil {
+		t.Fatalf("runBatchWith: %v", err)
+	}
+
+	wantOK := []bool{true, false, true, false}
+	dec := json.NewDecoder(&out)
+	for i, ok := range wantOK {
+		var res batchResult
+		if err := dec.Decode(&res); err != nil {
+			t.Fatalf("decode result %d: %v", i, err)
+		}
+		if res.Index != i {
+			t.Errorf("result %d: got index %d, want %d", i, res.Index, i)
+		}
+		if res.OK != ok {
+			t.Errorf("result %d: got OK=%v, want %v", i, res.OK, ok)
+		}
+		if !ok {
+			if res.Error == nil || res.Error.Kind != "decode_error" {
+				t.Errorf("result %d: got error %+v, want kind decode_error", i, res.Error)
+			}
+		}
+	}
+}
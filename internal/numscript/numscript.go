@@ -1,20 +1,37 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/Jeffail/gabs/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/PagoPlus/numscriptex/internal/analysis"
 	"github.com/PagoPlus/numscriptex/internal/interpreter"
+	"github.com/PagoPlus/numscriptex/internal/lsp"
 	"github.com/PagoPlus/numscriptex/internal/parser"
-	"github.com/PagoPlus/numscriptex/internal/utils"
+	"github.com/PagoPlus/numscriptex/internal/reporters"
+	"github.com/PagoPlus/numscriptex/internal/server"
+)
+
+// Version, GitRef and BuildDate are populated at build time via
+// -ldflags "-X main.Version=... -X main.GitRef=... -X main.BuildDate=...".
+var (
+	Version   = "dev"
+	GitRef    = "unknown"
+	BuildDate = "unknown"
 )
 
 type RunInputOpts struct {
@@ -22,24 +39,10 @@ type RunInputOpts struct {
 	Variables map[string]string            `json:"variables"`
 	Meta      interpreter.AccountsMetadata `json:"metadata"`
 	Balances  interpreter.Balances         `json:"balances"`
+	Features  []string                     `json:"features"`
 }
 
-func SeverityToString(s analysis.Severity) string {
-	switch s {
-	case analysis.ErrorSeverity:
-		return "error"
-	case analysis.WarningSeverity:
-		return "warning"
-	case analysis.Information:
-		return "info"
-	case analysis.Hint:
-		return "hint"
-	default:
-		return utils.NonExhaustiveMatchPanic[string](s)
-	}
-}
-
-func check() {
+func check(reporter reporters.Reporter) {
 	dat, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		os.Stderr.Write([]byte(err.Error()))
@@ -54,39 +57,25 @@ func check() {
 		return p2.GtEq(p1)
 	})
 
-	hasErrors := false
-	jsonObj := gabs.New()
-	jsonObj.Array("errors")
-	for _, d := range res.Diagnostics {
-		if d.Kind.Severity() == analysis.ErrorSeverity {
-			hasErrors = true
-		}
-		errLevel := SeverityToString(d.Kind.Severity())
-
-		subJsonObj := gabs.New()
-		subJsonObj.Set(d.Range.Start.Line, "line")
-		subJsonObj.Set(d.Range.Start.Character, "character")
-		subJsonObj.Set(errLevel, "level")
-		subJsonObj.Set(d.Kind.Message(), "error")
-
-		jsonObj.ArrayAppend(subJsonObj, "errors")
-	}
-
-	if hasErrors {
-		jsonObj.Set(false, "valid")
-	} else {
-		jsonObj.Set(true, "valid")
+	if _, err := reporter.Report(os.Stdout, "<stdin>", res); err != nil {
+		os.Stderr.Write([]byte(err.Error()))
+		os.Exit(1)
 	}
-
-	fmt.Println(jsonObj.String())
 }
 
+var checkOutput string
+
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Check a numscript file",
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		check()
+		reporter, err := reporters.ByName(checkOutput)
+		if err != nil {
+			os.Stderr.Write([]byte(err.Error()))
+			os.Exit(1)
+		}
+		check(reporter)
 	},
 }
 
@@ -109,11 +98,20 @@ func run() {
 
 	parseResult := parser.Parse(opt.Script)
 	if len(parseResult.Errors) != 0 {
-		os.Stderr.Write([]byte(parser.ParseErrorsToString(parseResult.Errors, opt.Script)))
+		writeErrorEnvelope(errorDetail{
+			Kind:    "parse_error",
+			Message: parser.ParseErrorsToString(parseResult.Errors, opt.Script),
+			Range:   parseErrorRange(opt.Script),
+		})
 		os.Exit(1)
 	}
 
-	featureFlags := make(map[string]struct{})
+	if missing := interpreter.MissingVariables(opt.Script, opt.Variables); len(missing) > 0 {
+		writeErrorEnvelope(runtimeErrorDetail(missingVariablesError(missing)))
+		os.Exit(1)
+	}
+
+	featureFlags := interpreter.NewFeatureSet(append(opt.Features, runFeatures...))
 	result, err := interpreter.RunProgram(
 		context.Background(),
 		parseResult.Value,
@@ -126,10 +124,11 @@ func run() {
 	)
 
 	if err != nil {
+		writeErrorEnvelope(runtimeErrorDetail(err))
 		os.Exit(1)
 	}
 
-	out, err := json.Marshal(result)
+	out, err := withVersionField(result)
 	if err != nil {
 		os.Exit(1)
 	}
@@ -137,27 +136,384 @@ func run() {
 	os.Stdout.Write(out)
 }
 
+// position and errorRange mirror the line/character shape analysis.Range
+// uses for diagnostics, so a check and a run failure can be rendered the
+// same way by a caller.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type errorRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// errorDetail mirrors the shape of an analysis.Diagnostic closely enough
+// that callers can treat parse and runtime failures uniformly: a stable
+// kind, a human message, and the source range implicated in the failure
+// when one is known.
+type errorDetail struct {
+	Kind    string      `json:"kind"`
+	Message string      `json:"message"`
+	Range   *errorRange `json:"range,omitempty"`
+}
+
+type errorEnvelope struct {
+	OK    bool        `json:"ok"`
+	Error errorDetail `json:"error"`
+}
+
+// parseErrorRange locates the first error-severity diagnostic CheckSource
+// reports for script, reusing the same analyzer pass `check` runs so a
+// parse failure's range matches what `numscript check` would report for the
+// same script.
+func parseErrorRange(script string) *errorRange {
+	res := analysis.CheckSource(script)
+	for _, d := range res.Diagnostics {
+		if d.Kind.Severity() != analysis.ErrorSeverity {
+			continue
+		}
+		return &errorRange{
+			Start: position{Line: d.Range.Start.Line, Character: d.Range.Start.Character},
+			End:   position{Line: d.Range.End.Line, Character: d.Range.End.Character},
+		}
+	}
+	return nil
+}
+
+// missingVariablesError builds the classified error run and runOne return
+// when the caller didn't supply a value for every variable the script
+// declares, so runtimeErrorDetail reports "missing_variable" instead of
+// letting RunProgram fail later with an unclassified error.
+func missingVariablesError(missing []string) *interpreter.RuntimeError {
+	return &interpreter.RuntimeError{
+		Kind: interpreter.MissingVariableError,
+		Msg:  fmt.Sprintf("missing value for variable(s): %s", strings.Join(missing, ", ")),
+	}
+}
+
+func runtimeErrorDetail(err error) errorDetail {
+	kind, _ := interpreter.Classify(err)
+	detail := errorDetail{Kind: string(kind), Message: err.Error()}
+
+	if runtimeErr, ok := interpreter.AsRuntimeError(err); ok && runtimeErr.Range != nil {
+		detail.Range = &errorRange{
+			Start: position{Line: runtimeErr.Range.Start.Line, Character: runtimeErr.Range.Start.Character},
+			End:   position{Line: runtimeErr.Range.End.Line, Character: runtimeErr.Range.End.Character},
+		}
+	}
+
+	return detail
+}
+
+// writeErrorEnvelope writes a structured {"ok":false,"error":{...}} envelope
+// to stdout, so callers can react to run failures programmatically instead
+// of parsing stderr text.
+func writeErrorEnvelope(detail errorDetail) {
+	out, err := json.Marshal(errorEnvelope{OK: false, Error: detail})
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Stdout.Write(out)
+}
+
+// parseCache memoizes parser.Parse by script hash, so a batch of requests
+// that repeat the same script (e.g. one transaction template evaluated with
+// many different variable sets) only pays the parse cost once.
+var parseCache sync.Map // map[string]parser.ParseResult
+
+func parseCached(script string) parser.ParseResult {
+	sum := sha256.Sum256([]byte(script))
+	key := hex.EncodeToString(sum[:])
+
+	if cached, ok := parseCache.Load(key); ok {
+		return cached.(parser.ParseResult)
+	}
+
+	result := parser.Parse(script)
+	parseCache.Store(key, result)
+	return result
+}
+
+type batchResult struct {
+	Index int             `json:"index"`
+	OK    bool            `json:"ok"`
+	Error *errorDetail    `json:"error,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func runOne(ctx context.Context, opt RunInputOpts) batchResult {
+	parseResult := parseCached(opt.Script)
+	if len(parseResult.Errors) != 0 {
+		return batchResult{OK: false, Error: &errorDetail{
+			Kind:    "parse_error",
+			Message: parser.ParseErrorsToString(parseResult.Errors, opt.Script),
+			Range:   parseErrorRange(opt.Script),
+		}}
+	}
+
+	if missing := interpreter.MissingVariables(opt.Script, opt.Variables); len(missing) > 0 {
+		detail := runtimeErrorDetail(missingVariablesError(missing))
+		return batchResult{OK: false, Error: &detail}
+	}
+
+	featureFlags := interpreter.NewFeatureSet(append(opt.Features, runFeatures...))
+	result, err := interpreter.RunProgram(
+		ctx,
+		parseResult.Value,
+		opt.Variables,
+		interpreter.StaticStore{
+			Balances: opt.Balances,
+			Meta:     opt.Meta,
+		},
+		featureFlags,
+	)
+	if err != nil {
+		detail := runtimeErrorDetail(err)
+		return batchResult{OK: false, Error: &detail}
+	}
+
+	value, err := withVersionField(result)
+	if err != nil {
+		detail := errorDetail{Kind: "unknown", Message: err.Error()}
+		return batchResult{OK: false, Error: &detail}
+	}
+	return batchResult{OK: true, Value: value}
+}
+
+// withVersionField marshals v and stamps a "numscriptVersion" field onto the
+// resulting object, so downstream tooling can gate behavior on the
+// interpreter version that produced a result.
+func withVersionField(v interface{}) (json.RawMessage, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(out, &obj); err != nil {
+		// v didn't marshal to a JSON object (e.g. a scalar); return it as-is.
+		return out, nil
+	}
+
+	versionJSON, err := json.Marshal(Version)
+	if err != nil {
+		return nil, err
+	}
+	obj["numscriptVersion"] = versionJSON
+
+	return json.Marshal(obj)
+}
+
+// runBatch reads newline-delimited RunInputOpts from in and writes a
+// matching NDJSON stream of batchResult to out, preserving input order
+// regardless of completion order across the worker pool.
+func runBatch(in io.Reader, out io.Writer, parallelism int, timeout time.Duration) error {
+	return runBatchWith(in, out, parallelism, func(opt RunInputOpts) batchResult {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return runOne(ctx, opt)
+	})
+}
+
+// runBatchWith is runBatch with the per-line evaluation step factored out, so
+// tests can exercise the decoding and ordering logic with a fake process
+// function instead of a real interpreter run.
+func runBatchWith(in io.Reader, out io.Writer, parallelism int, process func(RunInputOpts) batchResult) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	type job struct {
+		index     int
+		opt       RunInputOpts
+		decodeErr string
+	}
+
+	jobs := make(chan job)
+	results := make(chan batchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var res batchResult
+				if j.decodeErr != "" {
+					res = batchResult{OK: false, Error: &errorDetail{Kind: "decode_error", Message: j.decodeErr}}
+				} else {
+					res = process(j.opt)
+				}
+				res.Index = j.index
+				results <- res
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var scanErr error
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		index := 0
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+
+			opt := RunInputOpts{
+				Variables: make(map[string]string),
+				Meta:      make(interpreter.AccountsMetadata),
+				Balances:  make(interpreter.Balances),
+			}
+			if err := json.Unmarshal(line, &opt); err != nil {
+				jobs <- job{index: index, decodeErr: err.Error()}
+				index++
+				continue
+			}
+
+			jobs <- job{index: index, opt: opt}
+			index++
+		}
+		scanErr = scanner.Err()
+	}()
+
+	// Buffer results so they can be flushed to out in input order without
+	// blocking the worker pool on a slow writer.
+	pending := make(map[int]batchResult)
+	next := 0
+	encoder := json.NewEncoder(out)
+	for res := range results {
+		pending[res.Index] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+			delete(pending, next)
+			next++
+		}
+	}
+
+	return scanErr
+}
+
+var (
+	runFeatures    []string
+	runBatchMode   bool
+	runParallelism int
+	runTimeout     time.Duration
+)
+
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Evaluate a numscript file",
 	Long:  "Evaluate a numscript file, using the balances, the current metadata and the variables values as input.",
 	Run: func(cmd *cobra.Command, args []string) {
+		if runBatchMode {
+			if err := runBatch(os.Stdin, os.Stdout, runParallelism, runTimeout); err != nil {
+				os.Stderr.Write([]byte(err.Error()))
+				os.Exit(1)
+			}
+			return
+		}
 		run()
 	},
 }
 
+var featuresCmd = &cobra.Command{
+	Use:   "features",
+	Short: "List known interpreter feature flags",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(interpreter.KnownFeatures) == 0 {
+			fmt.Println("no feature flags are currently registered")
+			return
+		}
+		for _, f := range interpreter.KnownFeatures {
+			fmt.Printf("%s\t%s\n", f.Name, f.Description)
+		}
+	},
+}
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Start a numscript language server over stdio",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server := lsp.NewServer()
+		if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+			os.Stderr.Write([]byte(err.Error()))
+			os.Exit(1)
+		}
+	},
+}
+
+var serveListen string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a long-lived numscript evaluation server",
+	Long:  "Run a long-lived HTTP server exposing /check, /run and /rpc so callers can batch-evaluate scripts without paying process startup cost per invocation.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		srv := server.New(server.Options{Listen: serveListen})
+		if err := srv.ListenAndServe(ctx); err != nil {
+			os.Stderr.Write([]byte(err.Error()))
+			os.Exit(1)
+		}
+	},
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the numscript CLI version",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("numscript %s (commit %s, built %s)\n", Version, GitRef, BuildDate)
+	},
+}
+
 var rootCmd = &cobra.Command{
-	Use:   "numscript",
-	Short: "Numscript cli",
-	Long:  "Numscript cli",
+	Use:     "numscript",
+	Short:   "Numscript cli",
+	Long:    "Numscript cli",
+	Version: Version,
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
 }
 
 func main() {
+	reporters.NumscriptVersion = Version
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "address to listen on")
+	runCmd.Flags().StringArrayVar(&runFeatures, "feature", nil, "enable an experimental interpreter feature flag (repeatable)")
+	runCmd.Flags().BoolVar(&runBatchMode, "batch", false, "read newline-delimited RunInputOpts from stdin and write a matching NDJSON stream of results")
+	runCmd.Flags().IntVar(&runParallelism, "parallel", 1, "number of scripts to evaluate concurrently in --batch mode")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 30*time.Second, "per-request timeout in --batch mode")
+	checkCmd.Flags().StringVar(&checkOutput, "output", "json", "output format: json, sarif, junit, text")
+
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(lspCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(featuresCmd)
+	rootCmd.AddCommand(versionCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Stderr.Write([]byte(err.Error()))
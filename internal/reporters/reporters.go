@@ -0,0 +1,249 @@
+// Package reporters formats analysis.CheckSource results for consumption by
+// humans and CI systems.
+package reporters
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/PagoPlus/numscriptex/internal/analysis"
+)
+
+// NumscriptVersion is stamped onto JSONReporter output under
+// "numscriptVersion", so downstream tooling can gate behavior on the
+// interpreter version that produced a check result. main sets this once at
+// startup from build-time version metadata.
+var NumscriptVersion string
+
+// Reporter renders a single file's diagnostics to w.
+type Reporter interface {
+	// Report writes diagnostics for the file at path to w. It returns
+	// whether the file is valid (has no diagnostic of error severity).
+	Report(w io.Writer, path string, res analysis.CheckResult) (valid bool, err error)
+}
+
+// ByName resolves a Reporter from an --output flag value. It returns an
+// error for unknown names so the CLI can surface a clear usage message.
+func ByName(name string) (Reporter, error) {
+	switch name {
+	case "", "json":
+		return JSONReporter{}, nil
+	case "sarif":
+		return SARIFReporter{}, nil
+	case "junit":
+		return JUnitReporter{}, nil
+	case "text":
+		return TextReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", name)
+	}
+}
+
+func severityToString(s analysis.Severity) string {
+	switch s {
+	case analysis.ErrorSeverity:
+		return "error"
+	case analysis.WarningSeverity:
+		return "warning"
+	case analysis.Information:
+		return "info"
+	case analysis.Hint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// JSONReporter is the original `numscript check` output shape.
+type JSONReporter struct{}
+
+func (JSONReporter) Report(w io.Writer, path string, res analysis.CheckResult) (bool, error) {
+	hasErrors := false
+	jsonObj := gabs.New()
+	jsonObj.Array("errors")
+	for _, d := range res.Diagnostics {
+		level := severityToString(d.Kind.Severity())
+		if level == "error" {
+			hasErrors = true
+		}
+
+		subJsonObj := gabs.New()
+		subJsonObj.Set(d.Range.Start.Line, "line")
+		subJsonObj.Set(d.Range.Start.Character, "character")
+		subJsonObj.Set(level, "level")
+		subJsonObj.Set(d.Kind.Message(), "error")
+
+		jsonObj.ArrayAppend(subJsonObj, "errors")
+	}
+	jsonObj.Set(!hasErrors, "valid")
+	if NumscriptVersion != "" {
+		jsonObj.Set(NumscriptVersion, "numscriptVersion")
+	}
+
+	_, err := fmt.Fprintln(w, jsonObj.String())
+	return !hasErrors, err
+}
+
+// TextReporter prints one human-readable line per diagnostic.
+type TextReporter struct{}
+
+func (TextReporter) Report(w io.Writer, path string, res analysis.CheckResult) (bool, error) {
+	valid := true
+	for _, d := range res.Diagnostics {
+		level := severityToString(d.Kind.Severity())
+		if level == "error" {
+			valid = false
+		}
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n",
+			path, d.Range.Start.Line+1, d.Range.Start.Character+1, level, d.Kind.Message()); err != nil {
+			return valid, err
+		}
+	}
+	return valid, nil
+}
+
+// sarifRuleID derives a stable SARIF ruleId from a diagnostic kind, using
+// its Go type name so distinct diagnostic kinds never collide.
+func sarifRuleID(kind analysis.DiagnosticKind) string {
+	return fmt.Sprintf("%T", kind)
+}
+
+func sarifLevel(s analysis.Severity) string {
+	switch s {
+	case analysis.ErrorSeverity:
+		return "error"
+	case analysis.WarningSeverity:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SARIFReporter emits a single-run SARIF 2.1.0 log, for consumption by
+// code-scanning dashboards (e.g. GitHub code scanning).
+type SARIFReporter struct{}
+
+func (SARIFReporter) Report(w io.Writer, path string, res analysis.CheckResult) (bool, error) {
+	type sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+	}
+	type sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region sarifRegion `json:"region"`
+		} `json:"physicalLocation"`
+	}
+	type sarifResult struct {
+		RuleID    string            `json:"ruleId"`
+		Level     string            `json:"level"`
+		Message   map[string]string `json:"message"`
+		Locations []sarifLocation   `json:"locations"`
+	}
+
+	hasErrors := false
+	results := make([]sarifResult, 0, len(res.Diagnostics))
+	for _, d := range res.Diagnostics {
+		if d.Kind.Severity() == analysis.ErrorSeverity {
+			hasErrors = true
+		}
+
+		loc := sarifLocation{}
+		loc.PhysicalLocation.ArtifactLocation.URI = path
+		loc.PhysicalLocation.Region = sarifRegion{
+			StartLine:   d.Range.Start.Line + 1,
+			StartColumn: d.Range.Start.Character + 1,
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    sarifRuleID(d.Kind),
+			Level:     sarifLevel(d.Kind.Severity()),
+			Message:   map[string]string{"text": d.Kind.Message()},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	sarifLog := map[string]interface{}{
+		"$schema": "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]interface{}{
+			{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name": "numscript",
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	return !hasErrors, enc.Encode(sarifLog)
+}
+
+// JUnitReporter emits one <testcase> per file, so check failures surface in
+// CI systems that render JUnit XML.
+type JUnitReporter struct{}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitTestCase struct {
+	XMLName  xml.Name       `xml:"testcase"`
+	Name     string         `xml:"name,attr"`
+	Failures []junitFailure `xml:"failure,omitempty"`
+	Skipped  []junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+func (JUnitReporter) Report(w io.Writer, path string, res analysis.CheckResult) (bool, error) {
+	tc := junitTestCase{Name: path}
+	hasErrors := false
+
+	for _, d := range res.Diagnostics {
+		msg := fmt.Sprintf("%s:%d:%d: %s", path, d.Range.Start.Line+1, d.Range.Start.Character+1, d.Kind.Message())
+		if d.Kind.Severity() == analysis.ErrorSeverity {
+			hasErrors = true
+			tc.Failures = append(tc.Failures, junitFailure{Message: d.Kind.Message(), Text: msg})
+		} else {
+			tc.Skipped = append(tc.Skipped, junitSkipped{Message: msg})
+		}
+	}
+
+	suite := junitTestSuite{
+		Name:      "numscript check",
+		Tests:     1,
+		TestCases: []junitTestCase{tc},
+	}
+	if hasErrors {
+		suite.Failures = 1
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return !hasErrors, err
+	}
+	_, err := w.Write([]byte("\n"))
+	return !hasErrors, err
+}
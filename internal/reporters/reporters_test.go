@@ -0,0 +1,109 @@
+package reporters
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/PagoPlus/numscriptex/internal/analysis"
+)
+
+// fakeKind is a minimal analysis.DiagnosticKind for driving a reporter
+// without needing a real analyzer pass.
+type fakeKind struct {
+	severity analysis.Severity
+	message  string
+}
+
+func (k fakeKind) Severity() analysis.Severity { return k.severity }
+func (k fakeKind) Message() string             { return k.message }
+
+func fakeResult() analysis.CheckResult {
+	return analysis.CheckResult{
+		Diagnostics: []analysis.Diagnostic{
+			{
+				Kind: fakeKind{severity: analysis.ErrorSeverity, message: "undeclared variable $amount"},
+				Range: analysis.Range{
+					Start: analysis.Position{Line: 1, Character: 2},
+					End:   analysis.Position{Line: 1, Character: 9},
+				},
+			},
+			{
+				Kind: fakeKind{severity: analysis.WarningSeverity, message: "unused metadata key"},
+				Range: analysis.Range{
+					Start: analysis.Position{Line: 3, Character: 0},
+					End:   analysis.Position{Line: 3, Character: 4},
+				},
+			},
+		},
+	}
+}
+
+func TestSARIFReporter(t *testing.T) {
+	var buf bytes.Buffer
+	valid, err := SARIFReporter{}.Report(&buf, "script.num", fakeResult())
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if valid {
+		t.Error("got valid=true, want false: one diagnostic is error severity")
+	}
+
+	var log map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output isn't valid JSON: %v", err)
+	}
+	runs, ok := log["runs"].([]interface{})
+	if !ok || len(runs) != 1 {
+		t.Fatalf("got runs=%v, want a single run", log["runs"])
+	}
+	run := runs[0].(map[string]interface{})
+	results, ok := run["results"].([]interface{})
+	if !ok || len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	first := results[0].(map[string]interface{})
+	if first["level"] != "error" {
+		t.Errorf("got level %v, want error", first["level"])
+	}
+	loc := first["locations"].([]interface{})[0].(map[string]interface{})
+	region := loc["physicalLocation"].(map[string]interface{})["region"].(map[string]interface{})
+	if region["startLine"].(float64) != 2 {
+		t.Errorf("got startLine %v, want 2 (1-indexed)", region["startLine"])
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	valid, err := JUnitReporter{}.Report(&buf, "script.num", fakeResult())
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if valid {
+		t.Error("got valid=true, want false: one diagnostic is error severity")
+	}
+
+	var suite junitTestSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("output isn't valid JUnit XML: %v", err)
+	}
+	if len(suite.TestCases) != 1 {
+		t.Fatalf("got %d testcases, want 1", len(suite.TestCases))
+	}
+	tc := suite.TestCases[0]
+	if tc.Name != "script.num" {
+		t.Errorf("got testcase name %q, want script.num", tc.Name)
+	}
+	if len(tc.Failures) != 1 {
+		t.Fatalf("got %d failures, want 1 (only the error-severity diagnostic)", len(tc.Failures))
+	}
+	if !strings.Contains(tc.Failures[0].Message, "undeclared variable") {
+		t.Errorf("got failure message %q, want it to mention the error diagnostic", tc.Failures[0].Message)
+	}
+	if len(tc.Skipped) != 1 {
+		t.Fatalf("got %d skipped, want 1 (the warning-severity diagnostic)", len(tc.Skipped))
+	}
+}
@@ -0,0 +1,359 @@
+// Package server exposes numscript's check/run evaluation as a long-lived
+// HTTP and JSON-RPC 2.0 service, so callers that evaluate many scripts don't
+// pay a process startup cost per invocation. Each request still parses its
+// script.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/PagoPlus/numscriptex/internal/analysis"
+	"github.com/PagoPlus/numscriptex/internal/interpreter"
+	"github.com/PagoPlus/numscriptex/internal/parser"
+)
+
+// Options configures a Server.
+type Options struct {
+	Listen         string
+	RequestTimeout time.Duration
+	Logger         *slog.Logger
+}
+
+type metrics struct {
+	parseTotal  *prometheus.CounterVec
+	runTotal    *prometheus.CounterVec
+	latency     *prometheus.HistogramVec
+	errorsTotal *prometheus.CounterVec
+}
+
+// newMetrics registers its collectors into a registry scoped to this
+// Server instance, rather than the global DefaultRegisterer, so creating
+// more than one Server in a process (e.g. in tests) doesn't panic on
+// duplicate registration.
+func newMetrics(registry *prometheus.Registry) *metrics {
+	factory := promauto.With(registry)
+	return &metrics{
+		parseTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "numscript_parse_total",
+			Help: "Number of scripts parsed, by outcome.",
+		}, []string{"outcome"}),
+		runTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "numscript_run_total",
+			Help: "Number of scripts run, by outcome.",
+		}, []string{"outcome"}),
+		latency: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "numscript_request_duration_seconds",
+			Help:    "Latency of check/run requests, by endpoint.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		errorsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "numscript_diagnostics_total",
+			Help: "Number of diagnostics emitted by check, by severity.",
+		}, []string{"severity"}),
+	}
+}
+
+// Server is a long-lived numscript evaluation server.
+type Server struct {
+	opts    Options
+	logger  *slog.Logger
+	metrics *metrics
+	mux     *http.ServeMux
+}
+
+// New builds a Server with its routes registered.
+func New(opts Options) *Server {
+	if opts.RequestTimeout == 0 {
+		opts.RequestTimeout = 10 * time.Second
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	registry := prometheus.NewRegistry()
+	s := &Server{
+		opts:    opts,
+		logger:  logger,
+		metrics: newMetrics(registry),
+		mux:     http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/check", s.handleCheck)
+	s.mux.HandleFunc("/run", s.handleRun)
+	s.mux.HandleFunc("/rpc", s.handleRPC)
+	s.mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	return s
+}
+
+// ListenAndServe starts the HTTP server and blocks until ctx is cancelled,
+// at which point it gracefully shuts down.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:    s.opts.Listen,
+		Handler: s.mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("numscript server listening", "addr", s.opts.Listen)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.logger.Info("numscript server shutting down")
+		return httpServer.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+type checkRequest struct {
+	Script string `json:"script"`
+}
+
+type checkResponse struct {
+	Valid  bool        `json:"valid"`
+	Errors interface{} `json:"errors"`
+}
+
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer func() { s.metrics.latency.WithLabelValues("check").Observe(time.Since(start).Seconds()) }()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.RequestTimeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	var req checkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.metrics.parseTotal.WithLabelValues("decode_error").Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := analysis.CheckSource(req.Script)
+	hasErrors := false
+	errs := make([]map[string]interface{}, 0, len(res.Diagnostics))
+	for _, d := range res.Diagnostics {
+		severity := d.Kind.Severity()
+		s.metrics.errorsTotal.WithLabelValues(severityLabel(severity)).Inc()
+		if severity == analysis.ErrorSeverity {
+			hasErrors = true
+		}
+		errs = append(errs, map[string]interface{}{
+			"line":      d.Range.Start.Line,
+			"character": d.Range.Start.Character,
+			"level":     severityLabel(severity),
+			"error":     d.Kind.Message(),
+		})
+	}
+
+	if hasErrors {
+		s.metrics.parseTotal.WithLabelValues("invalid").Inc()
+	} else {
+		s.metrics.parseTotal.WithLabelValues("valid").Inc()
+	}
+
+	writeJSON(w, http.StatusOK, checkResponse{Valid: !hasErrors, Errors: errs})
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer func() { s.metrics.latency.WithLabelValues("run").Observe(time.Since(start).Seconds()) }()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.RequestTimeout)
+	defer cancel()
+
+	var opt struct {
+		Script    string                       `json:"script"`
+		Variables map[string]string            `json:"variables"`
+		Meta      interpreter.AccountsMetadata `json:"metadata"`
+		Balances  interpreter.Balances         `json:"balances"`
+	}
+	opt.Variables = make(map[string]string)
+	opt.Meta = make(interpreter.AccountsMetadata)
+	opt.Balances = make(interpreter.Balances)
+
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	parseResult := parser.Parse(opt.Script)
+	if len(parseResult.Errors) != 0 {
+		s.metrics.runTotal.WithLabelValues("parse_error").Inc()
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"ok":    false,
+			"error": parser.ParseErrorsToString(parseResult.Errors, opt.Script),
+		})
+		return
+	}
+
+	if missing := interpreter.MissingVariables(opt.Script, opt.Variables); len(missing) > 0 {
+		s.metrics.runTotal.WithLabelValues("run_error").Inc()
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"ok":    false,
+			"error": (&interpreter.RuntimeError{Kind: interpreter.MissingVariableError, Msg: "missing value for variable(s): " + strings.Join(missing, ", ")}).Error(),
+		})
+		return
+	}
+
+	result, err := interpreter.RunProgram(
+		ctx,
+		parseResult.Value,
+		opt.Variables,
+		interpreter.StaticStore{
+			Balances: opt.Balances,
+			Meta:     opt.Meta,
+		},
+		make(map[string]struct{}),
+	)
+	if err != nil {
+		s.metrics.runTotal.WithLabelValues("run_error").Inc()
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"ok":    false,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	s.metrics.runTotal.WithLabelValues("ok").Inc()
+	writeJSON(w, http.StatusOK, result)
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleRPC exposes /check and /run as JSON-RPC 2.0 methods "check" and
+// "run", for callers that already speak JSON-RPC to a ledger backend.
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recorder := &responseRecorder{header: http.Header{}}
+	bodyReader := &rawRequest{body: req.Params}
+	innerReq, _ := http.NewRequestWithContext(r.Context(), http.MethodPost, r.URL.String(), bodyReader)
+
+	switch req.Method {
+	case "check":
+		s.handleCheck(recorder, innerReq)
+	case "run":
+		s.handleRun(recorder, innerReq)
+	default:
+		writeJSON(w, http.StatusOK, rpcResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32601, Message: "method not found: " + req.Method},
+		})
+		return
+	}
+
+	if recorder.status != 0 && recorder.status >= 400 {
+		writeJSON(w, http.StatusOK, rpcResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: recorder.status, Message: strings.TrimSpace(string(recorder.body))},
+		})
+		return
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(recorder.body, &result); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error:   &rpcError{Code: -32603, Message: err.Error()},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// responseRecorder and rawRequest let handleRPC reuse the handleCheck and
+// handleRun http.HandlerFuncs without standing up a real HTTP round-trip.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (rr *responseRecorder) Header() http.Header { return rr.header }
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.body = append(rr.body, b...)
+	return len(b), nil
+}
+
+func (rr *responseRecorder) WriteHeader(status int) { rr.status = status }
+
+type rawRequest struct {
+	body []byte
+	pos  int
+}
+
+func (b *rawRequest) Read(p []byte) (int, error) {
+	if b.pos >= len(b.body) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.body[b.pos:])
+	b.pos += n
+	return n, nil
+}
+
+func severityLabel(s analysis.Severity) string {
+	switch s {
+	case analysis.ErrorSeverity:
+		return "error"
+	case analysis.WarningSeverity:
+		return "warning"
+	case analysis.Information:
+		return "info"
+	case analysis.Hint:
+		return "hint"
+	default:
+		return "unknown"
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}